@@ -0,0 +1,70 @@
+package plan
+
+// NodePlan is the full set of probes the plan agent running on a node should evaluate on an interval and report
+// the results of back to the planner.
+type NodePlan struct {
+	Probes map[string]Probe `json:"probes,omitempty"`
+}
+
+// Probe is a single liveness/readiness check the plan agent evaluates on an interval.
+type Probe struct {
+	InitialDelaySeconds int `json:"initialDelaySeconds,omitempty"`
+	TimeoutSeconds      int `json:"timeoutSeconds,omitempty"`
+	SuccessThreshold    int `json:"successThreshold,omitempty"`
+	FailureThreshold    int `json:"failureThreshold,omitempty"`
+
+	// StabilityWindowSeconds is how long a run of consecutive failures must persist before the agent reports the
+	// probe unhealthy, and RecoveryDelaySeconds is the equivalent hold-down before it reports the probe healthy
+	// again, both applied on top of SuccessThreshold/FailureThreshold to debounce the reported ProbeStatus.
+	StabilityWindowSeconds int `json:"stabilityWindowSeconds,omitempty"`
+	RecoveryDelaySeconds   int `json:"recoveryDelaySeconds,omitempty"`
+
+	// Exactly one of HTTPGetAction, TCPSocketAction, or ExecAction must be set, unless CertExpiryAction is set, in
+	// which case none of them is.
+	HTTPGetAction   HTTPGetAction   `json:"httpGetAction,omitempty"`
+	TCPSocketAction TCPSocketAction `json:"tcpSocketAction,omitempty"`
+	ExecAction      ExecAction      `json:"execAction,omitempty"`
+
+	// CertExpiryAction, when set, turns this probe into a certificate-expiry check instead of a liveness check:
+	// the agent parses each referenced PEM file and fails the probe once none of them have CriticalDays of
+	// validity left.
+	CertExpiryAction CertExpiryAction `json:"certExpiryAction,omitempty"`
+}
+
+// HTTPGetAction probes a component over HTTP(S), optionally presenting a client certificate.
+type HTTPGetAction struct {
+	URL        string `json:"url,omitempty"`
+	CACert     string `json:"caCert,omitempty"`
+	ClientCert string `json:"clientCert,omitempty"`
+	ClientKey  string `json:"clientKey,omitempty"`
+}
+
+// TCPSocketAction probes a component by opening (and immediately closing) a TCP connection.
+type TCPSocketAction struct {
+	Host string `json:"host,omitempty"`
+	Port string `json:"port,omitempty"`
+}
+
+// ExecAction probes a component by running Command on the node and checking its exit code.
+type ExecAction struct {
+	Command []string `json:"command,omitempty"`
+}
+
+// ProbeStatus is the agent-reported, debounced result of evaluating a Probe: Healthy only flips to false after
+// FailureThreshold consecutive failures sustained for StabilityWindowSeconds, and back to true only after
+// RecoveryDelaySeconds of consecutive successes.
+type ProbeStatus struct {
+	Healthy       bool   `json:"healthy,omitempty"`
+	Transitioning bool   `json:"transitioning,omitempty"`
+	LastReplyTime string `json:"lastReplyTime,omitempty"`
+}
+
+// CertExpiryAction watches one or more PEM-encoded certificate files for impending expiry.
+type CertExpiryAction struct {
+	// Files is the list of PEM files whose NotAfter is checked on every probe interval.
+	Files []string `json:"files,omitempty"`
+	// WarningDays is the number of days of remaining validity at which a warning-level event is raised.
+	WarningDays int `json:"warningDays,omitempty"`
+	// CriticalDays is the number of days of remaining validity at which the probe is reported as failed.
+	CriticalDays int `json:"criticalDays,omitempty"`
+}
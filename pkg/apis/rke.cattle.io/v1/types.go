@@ -0,0 +1,32 @@
+package v1
+
+import "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+
+// RKEControlPlane is the control-plane configuration for an RKE2/K3s-provisioned cluster.
+type RKEControlPlane struct {
+	Spec RKEControlPlaneSpec `json:"spec,omitempty"`
+}
+
+// RKEControlPlaneSpec configures the control plane's provisioning and runtime behavior.
+type RKEControlPlaneSpec struct {
+	KubernetesVersion   string     `json:"kubernetesVersion,omitempty"`
+	MachineGlobalConfig GenericMap `json:"machineGlobalConfig,omitempty"`
+
+	// CertificateExpiryWarningDays and CertificateExpiryCriticalDays override the default leaf-certificate expiry
+	// thresholds (30 and 7 days, respectively) used by the control plane's certificate-expiry probes.
+	CertificateExpiryWarningDays  int `json:"certificateExpiryWarningDays,omitempty"`
+	CertificateExpiryCriticalDays int `json:"certificateExpiryCriticalDays,omitempty"`
+
+	// ExtraProbes adds user-defined probes (for example for a custom CNI or an in-house sidecar running on
+	// control-plane nodes) on top of the built-in probe set, keyed by probe name.
+	ExtraProbes map[string]plan.Probe `json:"extraProbes,omitempty"`
+	// DisabledProbes suppresses bundled probes by name (built-in or from ExtraProbes) that don't apply to this
+	// cluster, e.g. disabling the Calico probe on a Cilium-based install.
+	DisabledProbes []string `json:"disabledProbes,omitempty"`
+}
+
+// GenericMap is a loosely typed bag of configuration keyed by argument name, mirroring the RKE2/K3s config file
+// schema (e.g. MachineGlobalConfig.Data["cni"]).
+type GenericMap struct {
+	Data map[string]interface{} `json:"-"`
+}
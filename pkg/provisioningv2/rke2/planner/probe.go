@@ -3,6 +3,7 @@ package planner
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
@@ -11,30 +12,102 @@ import (
 	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
 )
 
+const (
+	// DefaultCertificateExpiryLeafWarningDays is the default number of days of remaining validity on a leaf
+	// certificate at which a warning-level certificate expiry event is raised.
+	DefaultCertificateExpiryLeafWarningDays = 30
+	// DefaultCertificateExpiryLeafCriticalDays is the default number of days of remaining validity on a leaf
+	// certificate at which the owning probe is failed.
+	DefaultCertificateExpiryLeafCriticalDays = 7
+	// DefaultCertificateExpiryCAWarningDays is the default number of days of remaining validity on a CA
+	// certificate at which a warning-level certificate expiry event is raised.
+	DefaultCertificateExpiryCAWarningDays = 365
+	// DefaultCertificateExpiryCACriticalDays is the default number of days of remaining validity on a CA
+	// certificate at which the owning probe is failed.
+	DefaultCertificateExpiryCACriticalDays = 90
+
+	// certExpiryCAProbeSuffix and certExpiryLeafProbeSuffix are appended to the name of the probe whose TLS
+	// material is being watched, so that a single HTTPS probe can own both a CA and a leaf expiry probe.
+	certExpiryCAProbeSuffix   = "-cert-expiry-ca"
+	certExpiryLeafProbeSuffix = "-cert-expiry-leaf"
+
+	// EtcdListenMetricsURLsArgument is the etcd argument that, when explicitly cleared by the user, disables the
+	// metrics/health HTTP listener that the default "etcd" probe relies on.
+	EtcdListenMetricsURLsArgument = "listen-metrics-urls"
+
+	// EtcdArg is the config key under which etcd's rendered argument list is stored.
+	EtcdArg = "etcd-arg"
+
+	// CloudControllerManagerArg is the config key under which the cloud-controller-manager's rendered argument
+	// list is stored.
+	CloudControllerManagerArg = "cloud-controller-manager-arg"
+	// DefaultCloudControllerManagerDefaultSecurePort is the --secure-port cloud-controller-manager binds to when
+	// the user hasn't overridden it.
+	DefaultCloudControllerManagerDefaultSecurePort = "10258"
+	// DefaultCloudControllerManagerCertDir is the --cert-dir cloud-controller-manager uses when the user hasn't
+	// overridden it, prior to runtime substitution.
+	DefaultCloudControllerManagerCertDir = "/var/lib/rancher/%s/server/tls"
+	// DefaultCloudControllerManagerCert is the serving certificate file name cloud-controller-manager uses within
+	// DefaultCloudControllerManagerCertDir when the user hasn't set --tls-cert-file.
+	DefaultCloudControllerManagerCert = "cloud-controller-manager/cloud-controller-manager.crt"
+
+	// etcdQuorumFlapFailureThreshold replaces the "etcd" probe's normal FailureThreshold when every etcd machine is
+	// reporting unhealthy at once, so the whole-quorum flap guard in addProbes doesn't trip per-node failure
+	// handling (and the resulting drain/replace) over what is far more likely transient load than a real outage.
+	etcdQuorumFlapFailureThreshold = 1 << 30
+
+	// etcdQuorumFlapMaxSuppressionSeconds bounds how long applyEtcdQuorumFlapGuard will keep relaxing the "etcd"
+	// probe's FailureThreshold during a whole-quorum flap. Once every etcd machine has gone this long since its
+	// last successful reply, the guard stops suppressing so a genuine extended outage still trips normal
+	// per-node failure handling instead of being treated as transient load forever.
+	etcdQuorumFlapMaxSuppressionSeconds = 300
+)
+
+// etcdTCPProbe is used in place of the default HTTP health probe for etcd when the user has disabled
+// --listen-metrics-urls, falling back to a plain TCP dial of the client port.
+var etcdTCPProbe = plan.Probe{
+	InitialDelaySeconds:    1,
+	TimeoutSeconds:         5,
+	SuccessThreshold:       1,
+	FailureThreshold:       2,
+	StabilityWindowSeconds: 15,
+	RecoveryDelaySeconds:   5,
+	TCPSocketAction: plan.TCPSocketAction{
+		Host: "127.0.0.1",
+		Port: "2379",
+	},
+}
+
 var allProbes = map[string]plan.Probe{
 	"calico": {
-		InitialDelaySeconds: 1,
-		TimeoutSeconds:      5,
-		SuccessThreshold:    1,
-		FailureThreshold:    2,
+		InitialDelaySeconds:    1,
+		TimeoutSeconds:         5,
+		SuccessThreshold:       1,
+		FailureThreshold:       2,
+		StabilityWindowSeconds: 10,
+		RecoveryDelaySeconds:   5,
 		HTTPGetAction: plan.HTTPGetAction{
 			URL: "http://127.0.0.1:9099/liveness",
 		},
 	},
 	"etcd": {
-		InitialDelaySeconds: 1,
-		TimeoutSeconds:      5,
-		SuccessThreshold:    1,
-		FailureThreshold:    2,
+		InitialDelaySeconds:    1,
+		TimeoutSeconds:         5,
+		SuccessThreshold:       1,
+		FailureThreshold:       2,
+		StabilityWindowSeconds: 15,
+		RecoveryDelaySeconds:   5,
 		HTTPGetAction: plan.HTTPGetAction{
 			URL: "http://127.0.0.1:2381/health",
 		},
 	},
 	"kube-apiserver": {
-		InitialDelaySeconds: 1,
-		TimeoutSeconds:      5,
-		SuccessThreshold:    1,
-		FailureThreshold:    2,
+		InitialDelaySeconds:    1,
+		TimeoutSeconds:         5,
+		SuccessThreshold:       1,
+		FailureThreshold:       2,
+		StabilityWindowSeconds: 30,
+		RecoveryDelaySeconds:   10,
 		HTTPGetAction: plan.HTTPGetAction{
 			URL:        "https://127.0.0.1:6443/readyz",
 			CACert:     "/var/lib/rancher/%s/server/tls/server-ca.crt",
@@ -43,34 +116,217 @@ var allProbes = map[string]plan.Probe{
 		},
 	},
 	"kube-scheduler": {
-		InitialDelaySeconds: 1,
-		TimeoutSeconds:      5,
-		SuccessThreshold:    1,
-		FailureThreshold:    2,
+		InitialDelaySeconds:    1,
+		TimeoutSeconds:         5,
+		SuccessThreshold:       1,
+		FailureThreshold:       2,
+		StabilityWindowSeconds: 15,
+		RecoveryDelaySeconds:   5,
 		HTTPGetAction: plan.HTTPGetAction{
 			URL: "https://127.0.0.1:%s/healthz",
 		},
 	},
 	"kube-controller-manager": {
-		InitialDelaySeconds: 1,
-		TimeoutSeconds:      5,
-		SuccessThreshold:    1,
-		FailureThreshold:    2,
+		InitialDelaySeconds:    1,
+		TimeoutSeconds:         5,
+		SuccessThreshold:       1,
+		FailureThreshold:       2,
+		StabilityWindowSeconds: 15,
+		RecoveryDelaySeconds:   5,
+		HTTPGetAction: plan.HTTPGetAction{
+			URL: "https://127.0.0.1:%s/healthz",
+		},
+	},
+	"cloud-controller-manager": {
+		InitialDelaySeconds:    1,
+		TimeoutSeconds:         5,
+		SuccessThreshold:       1,
+		FailureThreshold:       2,
+		StabilityWindowSeconds: 15,
+		RecoveryDelaySeconds:   5,
 		HTTPGetAction: plan.HTTPGetAction{
 			URL: "https://127.0.0.1:%s/healthz",
 		},
 	},
 	"kubelet": {
+		InitialDelaySeconds:    1,
+		TimeoutSeconds:         5,
+		SuccessThreshold:       1,
+		FailureThreshold:       2,
+		StabilityWindowSeconds: 15,
+		RecoveryDelaySeconds:   5,
+		HTTPGetAction: plan.HTTPGetAction{
+			URL: "http://127.0.0.1:10248/healthz",
+		},
+	},
+}
+
+// certExpiryProbe builds a probe that watches the given PEM files for expiry, failing once none of them have
+// more than criticalDays of validity remaining. The plan agent is responsible for parsing each file's NotAfter,
+// publishing the rke2_certificate_expiration_seconds gauge, and raising a warning event at warningDays.
+func certExpiryProbe(files []string, warningDays, criticalDays int) plan.Probe {
+	return plan.Probe{
 		InitialDelaySeconds: 1,
 		TimeoutSeconds:      5,
 		SuccessThreshold:    1,
-		FailureThreshold:    2,
-		HTTPGetAction: plan.HTTPGetAction{
-			URL: "http://127.0.0.1:10248/healthz",
+		FailureThreshold:    1,
+		CertExpiryAction: plan.CertExpiryAction{
+			Files:        files,
+			WarningDays:  warningDays,
+			CriticalDays: criticalDays,
 		},
+	}
+}
+
+// certExpiryCANames lists the probes whose HTTPGetAction.CACert field holds a genuine CA bundle. Every other
+// probe that sets CACert (kube-scheduler, kube-controller-manager, cloud-controller-manager) only does so
+// because renderSecureProbe/replaceCACertAndPortForProbes stash that component's own serving certificate there
+// to configure the HTTPS client's trust root — for those, CACert is a leaf cert, not a CA, and must use the
+// leaf thresholds and feed the "-cert-expiry-leaf" probe rather than the "-cert-expiry-ca" one.
+var certExpiryCANames = map[string]bool{
+	"kube-apiserver": true,
+}
+
+// addCertExpiryProbes derives certificate-expiry probes from the TLS files referenced by each probe in
+// nodePlan.Probes and adds them to nodePlan: a "-cert-expiry-ca" probe for the CACert of probes listed in
+// certExpiryCANames (using the CA defaults), and one "-cert-expiry-leaf" probe covering the probe's remaining
+// CACert/ClientCert/ClientKey files, using the leaf thresholds configured on the RKEControlPlane (falling back to
+// the package defaults).
+func addCertExpiryProbes(nodePlan plan.NodePlan, controlPlane *rkev1.RKEControlPlane) plan.NodePlan {
+	leafWarningDays := DefaultCertificateExpiryLeafWarningDays
+	leafCriticalDays := DefaultCertificateExpiryLeafCriticalDays
+	if controlPlane.Spec.CertificateExpiryWarningDays > 0 {
+		leafWarningDays = controlPlane.Spec.CertificateExpiryWarningDays
+	}
+	if controlPlane.Spec.CertificateExpiryCriticalDays > 0 {
+		leafCriticalDays = controlPlane.Spec.CertificateExpiryCriticalDays
+	}
+
+	additions := map[string]plan.Probe{}
+	for name, probe := range nodePlan.Probes {
+		var leafFiles []string
+		if probe.HTTPGetAction.CACert != "" {
+			if certExpiryCANames[name] {
+				additions[name+certExpiryCAProbeSuffix] = certExpiryProbe([]string{probe.HTTPGetAction.CACert}, DefaultCertificateExpiryCAWarningDays, DefaultCertificateExpiryCACriticalDays)
+			} else {
+				leafFiles = append(leafFiles, probe.HTTPGetAction.CACert)
+			}
+		}
+		if probe.HTTPGetAction.ClientCert != "" {
+			leafFiles = append(leafFiles, probe.HTTPGetAction.ClientCert)
+		}
+		if probe.HTTPGetAction.ClientKey != "" {
+			leafFiles = append(leafFiles, probe.HTTPGetAction.ClientKey)
+		}
+		if len(leafFiles) > 0 {
+			additions[name+certExpiryLeafProbeSuffix] = certExpiryProbe(leafFiles, leafWarningDays, leafCriticalDays)
+		}
+	}
+
+	for name, probe := range additions {
+		nodePlan.Probes[name] = probe
+	}
+	return nodePlan
+}
+
+// etcdMetricsExplicitlyDisabled reports whether the etcd argument list explicitly sets --listen-metrics-urls to
+// an empty value, which disables the HTTP listener the default "etcd" probe depends on.
+func etcdMetricsExplicitlyDisabled(arg interface{}) bool {
+	for _, raw := range convert.ToStringSlice(arg) {
+		if raw == "--"+EtcdListenMetricsURLsArgument+"=" {
+			return true
+		}
+	}
+	return false
+}
+
+// etcdQuorumProbesAllFailing returns true if every etcd machine in the control plane is currently reporting a
+// failed "etcd" probe. When the whole quorum fails at once it is far more likely that etcd is under transient
+// load than that every member failed independently, so callers should treat the quorum as healthy for
+// scheduling purposes rather than draining or replacing every member simultaneously.
+func etcdQuorumProbesAllFailing(statuses map[string]plan.ProbeStatus) bool {
+	if len(statuses) == 0 {
+		return false
+	}
+	for _, status := range statuses {
+		if status.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// etcdQuorumFlapWithinSuppressionWindow returns true if every etcd machine's last successful reply is both
+// present and no older than etcdQuorumFlapMaxSuppressionSeconds, i.e. the whole-quorum flap guard is still
+// within the window where the outage is more likely transient load than a real failure. A missing or
+// unparseable LastReplyTime is treated as maximally stale rather than skipped, since a member that has never
+// successfully replied must not suppress FailureThreshold indefinitely.
+func etcdQuorumFlapWithinSuppressionWindow(statuses map[string]plan.ProbeStatus, now time.Time) bool {
+	for _, status := range statuses {
+		lastReply, err := time.Parse(time.RFC3339, status.LastReplyTime)
+		if err != nil || now.Sub(lastReply) > etcdQuorumFlapMaxSuppressionSeconds*time.Second {
+			return false
+		}
+	}
+	return true
+}
+
+// applyEtcdQuorumFlapGuard relaxes the "etcd" probe's FailureThreshold when etcdQuorumProbesAllFailing reports that
+// every etcd machine is currently failing its probe at once, so the flap doesn't trip per-node failure handling.
+// The relaxation is only honored for etcdQuorumFlapMaxSuppressionSeconds: once the whole quorum has gone that
+// long without a successful reply, the guard backs off and lets the normal FailureThreshold apply, since by then
+// this looks like a real outage rather than transient load.
+func applyEtcdQuorumFlapGuard(nodePlan plan.NodePlan, etcdProbeStatuses map[string]plan.ProbeStatus) plan.NodePlan {
+	etcdProbe, ok := nodePlan.Probes["etcd"]
+	if !ok || !etcdQuorumProbesAllFailing(etcdProbeStatuses) || !etcdQuorumFlapWithinSuppressionWindow(etcdProbeStatuses, time.Now()) {
+		return nodePlan
+	}
+	etcdProbe.FailureThreshold = etcdQuorumFlapFailureThreshold
+	nodePlan.Probes["etcd"] = etcdProbe
+	return nodePlan
+}
+
+// controlPlaneSecureProbe describes a control-plane component whose probe is rendered by renderSecureProbe, i.e.
+// one whose --secure-port and TLS cert location can be overridden by the user. Adding a new component (for
+// example konnectivity-server or kine) only requires appending an entry here.
+type controlPlaneSecureProbe struct {
+	name              string
+	argKey            string
+	defaultSecurePort string
+	certDir           string
+	cert              string
+}
+
+var controlPlaneSecureProbes = []controlPlaneSecureProbe{
+	{
+		name:              "kube-controller-manager",
+		argKey:            KubeControllerManagerArg,
+		defaultSecurePort: DefaultKubeControllerManagerDefaultSecurePort,
+		certDir:           DefaultKubeControllerManagerCertDir,
+		cert:              DefaultKubeControllerManagerCert,
+	},
+	{
+		name:              "kube-scheduler",
+		argKey:            KubeSchedulerArg,
+		defaultSecurePort: DefaultKubeSchedulerDefaultSecurePort,
+		certDir:           DefaultKubeSchedulerCertDir,
+		cert:              DefaultKubeSchedulerCert,
+	},
+	{
+		name:              "cloud-controller-manager",
+		argKey:            CloudControllerManagerArg,
+		defaultSecurePort: DefaultCloudControllerManagerDefaultSecurePort,
+		certDir:           DefaultCloudControllerManagerCertDir,
+		cert:              DefaultCloudControllerManagerCert,
 	},
 }
 
+// cloudControllerManagerEnabled returns true unless the cluster has explicitly disabled the built-in
+// cloud-controller-manager via the disable-cloud-controller machine global config setting.
+func cloudControllerManagerEnabled(controlPlane *rkev1.RKEControlPlane) bool {
+	return !convert.ToBool(controlPlane.Spec.MachineGlobalConfig.Data["disable-cloud-controller"])
+}
+
 func isCalico(controlPlane *rkev1.RKEControlPlane, runtime string) bool {
 	if runtime != rancherruntime.RuntimeRKE2 {
 		return false
@@ -106,14 +362,68 @@ func renderSecureProbe(arg interface{}, rawProbe plan.Probe, runtime string, def
 	return replaceCACertAndPortForProbes(rawProbe, TLSCert, securePort)
 }
 
+// isProbeDisabled returns true if the given probe name is listed in controlPlane.Spec.DisabledProbes.
+func isProbeDisabled(controlPlane *rkev1.RKEControlPlane, name string) bool {
+	for _, disabled := range controlPlane.Spec.DisabledProbes {
+		if disabled == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateProbeAction ensures a probe sets exactly one of HTTPGetAction, TCPSocketAction, or ExecAction, since the
+// agent needs an unambiguous check to run.
+func validateProbeAction(name string, probe plan.Probe) error {
+	var set int
+	if probe.HTTPGetAction.URL != "" {
+		set++
+	}
+	if probe.TCPSocketAction.Port != "" {
+		set++
+	}
+	if len(probe.ExecAction.Command) > 0 {
+		set++
+	}
+	if set != 1 {
+		return fmt.Errorf("probe %s must set exactly one of httpGetAction, tcpSocketAction, or execAction", name)
+	}
+	return nil
+}
+
+// validateProbes ensures that ExtraProbes reference a non-empty check and that DisabledProbes only names probes
+// that actually exist (either built in or user-defined), returning an error describing the first problem found.
+func validateProbes(controlPlane *rkev1.RKEControlPlane) error {
+	for name, probe := range controlPlane.Spec.ExtraProbes {
+		if err := validateProbeAction(name, probe); err != nil {
+			return err
+		}
+	}
+	for _, name := range controlPlane.Spec.DisabledProbes {
+		if _, ok := allProbes[name]; ok {
+			continue
+		}
+		if _, ok := controlPlane.Spec.ExtraProbes[name]; ok {
+			continue
+		}
+		return fmt.Errorf("disabledProbes references unknown probe %s", name)
+	}
+	return nil
+}
+
 // addProbes adds probes for the machine (based on type of machine) to the nodePlan and returns the nodePlan and an error
-// if one occurred.
-func (p *Planner) addProbes(nodePlan plan.NodePlan, controlPlane *rkev1.RKEControlPlane, machine *capi.Machine, config map[string]interface{}) (plan.NodePlan, error) {
+// if one occurred. etcdProbeStatuses is the most recently reported health of every etcd machine's "etcd" probe,
+// keyed by machine name, and is used to guard against a whole-quorum flap being treated as a real outage.
+func (p *Planner) addProbes(nodePlan plan.NodePlan, controlPlane *rkev1.RKEControlPlane, machine *capi.Machine, config map[string]interface{}, etcdProbeStatuses map[string]plan.ProbeStatus) (plan.NodePlan, error) {
 	var (
 		runtime    = rancherruntime.GetRuntime(controlPlane.Spec.KubernetesVersion)
 		probeNames []string
 	)
 
+	if err := validateProbes(controlPlane); err != nil {
+		return nodePlan, err
+	}
+
 	nodePlan.Probes = map[string]plan.Probe{}
 
 	if runtime != rancherruntime.RuntimeK3S && isEtcd(machine) {
@@ -121,8 +431,12 @@ func (p *Planner) addProbes(nodePlan plan.NodePlan, controlPlane *rkev1.RKEContr
 	}
 	if isControlPlane(machine) {
 		probeNames = append(probeNames, "kube-apiserver")
-		probeNames = append(probeNames, "kube-controller-manager")
-		probeNames = append(probeNames, "kube-scheduler")
+		for _, spc := range controlPlaneSecureProbes {
+			if spc.name == "cloud-controller-manager" && !cloudControllerManagerEnabled(controlPlane) {
+				continue
+			}
+			probeNames = append(probeNames, spc.name)
+		}
 	}
 	if !(IsOnlyEtcd(machine) && runtime == rancherruntime.RuntimeK3S) {
 		// k3s doesn't run the kubelet on etcd only nodes
@@ -133,34 +447,95 @@ func (p *Planner) addProbes(nodePlan plan.NodePlan, controlPlane *rkev1.RKEContr
 	}
 
 	for _, probeName := range probeNames {
+		if isProbeDisabled(controlPlane, probeName) {
+			continue
+		}
 		nodePlan.Probes[probeName] = allProbes[probeName]
 	}
 
+	if _, ok := nodePlan.Probes["etcd"]; ok && etcdMetricsExplicitlyDisabled(config[EtcdArg]) {
+		nodePlan.Probes["etcd"] = etcdTCPProbe
+	}
+
+	nodePlan = applyEtcdQuorumFlapGuard(nodePlan, etcdProbeStatuses)
+
 	nodePlan.Probes = replaceRuntimeForProbes(nodePlan.Probes, runtime)
 
 	if isControlPlane(machine) {
-		kcmProbe, err := renderSecureProbe(config[KubeControllerManagerArg], nodePlan.Probes["kube-controller-manager"], rancherruntime.GetRuntime(controlPlane.Spec.KubernetesVersion), DefaultKubeControllerManagerDefaultSecurePort, DefaultKubeControllerManagerCertDir, DefaultKubeControllerManagerCert)
-		if err != nil {
-			return nodePlan, err
+		for _, spc := range controlPlaneSecureProbes {
+			probe, ok := nodePlan.Probes[spc.name]
+			if !ok {
+				continue
+			}
+			rendered, err := renderSecureProbe(config[spc.argKey], probe, runtime, spc.defaultSecurePort, spc.certDir, spc.cert)
+			if err != nil {
+				return nodePlan, err
+			}
+			nodePlan.Probes[spc.name] = rendered
 		}
-		nodePlan.Probes["kube-controller-manager"] = kcmProbe
+	}
 
-		ksProbe, err := renderSecureProbe(config[KubeSchedulerArg], nodePlan.Probes["kube-scheduler"], rancherruntime.GetRuntime(controlPlane.Spec.KubernetesVersion), DefaultKubeSchedulerDefaultSecurePort, DefaultKubeSchedulerCertDir, DefaultKubeSchedulerCert)
-		if err != nil {
-			return nodePlan, err
+	for name, probe := range controlPlane.Spec.ExtraProbes {
+		if isProbeDisabled(controlPlane, name) {
+			continue
 		}
-		nodePlan.Probes["kube-scheduler"] = ksProbe
+		nodePlan.Probes[name] = probe
 	}
+
+	nodePlan = addCertExpiryProbes(nodePlan, controlPlane)
+
 	return nodePlan, nil
 }
 
-// replaceCACertAndPortForProbes adds/replaces the CACert and URL with rendered values based on the values provided.
+// collectEtcdProbeStatuses builds the etcdProbeStatuses snapshot addProbes needs for its whole-quorum flap
+// guard: the most recently reported "etcd" plan.ProbeStatus for every etcd machine, keyed by machine name.
+// reportedEtcdProbeStatus reads a single machine's last-reported "etcd" probe status back from cluster state
+// (e.g. the machine's applied plan status); a machine it reports ok=false for (nothing reported yet) is left
+// out of the snapshot rather than assumed healthy or unhealthy.
+func collectEtcdProbeStatuses(etcdMachines []*capi.Machine, reportedEtcdProbeStatus func(*capi.Machine) (plan.ProbeStatus, bool)) map[string]plan.ProbeStatus {
+	statuses := map[string]plan.ProbeStatus{}
+	for _, machine := range etcdMachines {
+		if status, ok := reportedEtcdProbeStatus(machine); ok {
+			statuses[machine.Name] = status
+		}
+	}
+	return statuses
+}
+
+// addProbesForCluster wires the whole-quorum flap guard up to live cluster state: it snapshots every etcd
+// machine's currently reported "etcd" probe status via collectEtcdProbeStatuses, then calls addProbes once per
+// machine so every machine in the cluster sees the same quorum-wide view, returning each machine's resulting
+// plan.NodePlan keyed by machine name.
+func (p *Planner) addProbesForCluster(controlPlane *rkev1.RKEControlPlane, etcdMachines, allMachines []*capi.Machine, configForMachine func(*capi.Machine) map[string]interface{}, reportedEtcdProbeStatus func(*capi.Machine) (plan.ProbeStatus, bool)) (map[string]plan.NodePlan, error) {
+	etcdProbeStatuses := collectEtcdProbeStatuses(etcdMachines, reportedEtcdProbeStatus)
+
+	nodePlans := map[string]plan.NodePlan{}
+	for _, machine := range allMachines {
+		nodePlan, err := p.addProbes(plan.NodePlan{}, controlPlane, machine, configForMachine(machine), etcdProbeStatuses)
+		if err != nil {
+			return nil, fmt.Errorf("adding probes for machine %s: %w", machine.Name, err)
+		}
+		nodePlans[machine.Name] = nodePlan
+	}
+	return nodePlans, nil
+}
+
+// replaceCACertAndPortForProbes adds/replaces the CACert and port with rendered values based on the values
+// provided, rendering the port into whichever of HTTPGetAction.URL or TCPSocketAction.Port the probe uses.
 func replaceCACertAndPortForProbes(probe plan.Probe, cacert, port string) (plan.Probe, error) {
-	if cacert == "" || port == "" {
-		return plan.Probe{}, fmt.Errorf("CA cert (%s) or port (%s) not defined properly", cacert, port)
+	if port == "" {
+		return plan.Probe{}, fmt.Errorf("port (%s) not defined properly", port)
+	}
+	switch {
+	case probe.HTTPGetAction.URL != "":
+		if cacert == "" {
+			return plan.Probe{}, fmt.Errorf("CA cert (%s) not defined properly", cacert)
+		}
+		probe.HTTPGetAction.CACert = cacert
+		probe.HTTPGetAction.URL = fmt.Sprintf(probe.HTTPGetAction.URL, port)
+	case probe.TCPSocketAction.Port != "":
+		probe.TCPSocketAction.Port = replaceRuntime(probe.TCPSocketAction.Port, port)
 	}
-	probe.HTTPGetAction.CACert = cacert
-	probe.HTTPGetAction.URL = fmt.Sprintf(probe.HTTPGetAction.URL, port)
 	return probe, nil
 }
 
@@ -170,6 +545,17 @@ func replaceRuntimeForProbes(probes map[string]plan.Probe, runtime string) map[s
 		v.HTTPGetAction.CACert = replaceRuntime(v.HTTPGetAction.CACert, runtime)
 		v.HTTPGetAction.ClientCert = replaceRuntime(v.HTTPGetAction.ClientCert, runtime)
 		v.HTTPGetAction.ClientKey = replaceRuntime(v.HTTPGetAction.ClientKey, runtime)
+		v.TCPSocketAction.Host = replaceRuntime(v.TCPSocketAction.Host, runtime)
+		if len(v.ExecAction.Command) > 0 {
+			// Copy before mutating: v.ExecAction.Command is a slice header pointing at the same backing array
+			// as whatever Probe this came from (e.g. a package-level allProbes literal or a user's ExtraProbes
+			// entry), which is shared across every call to addProbes for every machine.
+			command := append([]string(nil), v.ExecAction.Command...)
+			for i, arg := range command {
+				command[i] = replaceRuntime(arg, runtime)
+			}
+			v.ExecAction.Command = command
+		}
 		result[k] = v
 	}
 	return result
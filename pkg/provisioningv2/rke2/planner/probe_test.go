@@ -0,0 +1,319 @@
+package planner
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+func TestAddCertExpiryProbesCAvsLeaf(t *testing.T) {
+	nodePlan := plan.NodePlan{
+		Probes: map[string]plan.Probe{
+			"kube-apiserver": {
+				HTTPGetAction: plan.HTTPGetAction{
+					CACert:     "/var/lib/rancher/rke2/server/tls/server-ca.crt",
+					ClientCert: "/var/lib/rancher/rke2/server/tls/client-kube-apiserver.crt",
+					ClientKey:  "/var/lib/rancher/rke2/server/tls/client-kube-apiserver.key",
+				},
+			},
+			"kube-scheduler": {
+				HTTPGetAction: plan.HTTPGetAction{
+					CACert: "/var/lib/rancher/rke2/server/tls/kube-scheduler/kube-scheduler.crt",
+				},
+			},
+		},
+	}
+	controlPlane := &rkev1.RKEControlPlane{}
+
+	result := addCertExpiryProbes(nodePlan, controlPlane)
+
+	if _, ok := result.Probes["kube-apiserver"+certExpiryCAProbeSuffix]; !ok {
+		t.Error("expected kube-apiserver to get a CA expiry probe for its real CA")
+	}
+	if _, ok := result.Probes["kube-apiserver"+certExpiryLeafProbeSuffix]; !ok {
+		t.Error("expected kube-apiserver to get a leaf expiry probe for its client cert/key")
+	}
+	if _, ok := result.Probes["kube-scheduler"+certExpiryCAProbeSuffix]; ok {
+		t.Error("kube-scheduler's CACert is its own serving cert, not a CA; it should not get a CA expiry probe")
+	}
+	leaf, ok := result.Probes["kube-scheduler"+certExpiryLeafProbeSuffix]
+	if !ok {
+		t.Fatal("expected kube-scheduler's serving cert to be watched by a leaf expiry probe")
+	}
+	if leaf.CertExpiryAction.CriticalDays != DefaultCertificateExpiryLeafCriticalDays {
+		t.Errorf("expected kube-scheduler's expiry probe to use leaf thresholds, got criticalDays=%d", leaf.CertExpiryAction.CriticalDays)
+	}
+}
+
+func TestValidateProbesExtraAndDisabled(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{
+		Spec: rkev1.RKEControlPlaneSpec{
+			ExtraProbes: map[string]plan.Probe{
+				"my-cni": {HTTPGetAction: plan.HTTPGetAction{URL: "http://127.0.0.1:9000/healthz"}},
+			},
+			DisabledProbes: []string{"calico", "my-cni"},
+		},
+	}
+
+	if err := validateProbes(controlPlane); err != nil {
+		t.Fatalf("expected valid probes and disabled names to pass validation, got %v", err)
+	}
+
+	if !isProbeDisabled(controlPlane, "calico") {
+		t.Error("expected built-in probe 'calico' to be disabled")
+	}
+	if !isProbeDisabled(controlPlane, "my-cni") {
+		t.Error("expected extra probe 'my-cni' to be disabled")
+	}
+	if isProbeDisabled(controlPlane, "kubelet") {
+		t.Error("did not expect 'kubelet' to be disabled")
+	}
+
+	controlPlane.Spec.DisabledProbes = []string{"does-not-exist"}
+	if err := validateProbes(controlPlane); err == nil {
+		t.Error("expected validateProbes to reject a disabledProbes entry that names no known probe")
+	}
+}
+
+func TestValidateProbesRejectsEmptyExtraProbe(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{
+		Spec: rkev1.RKEControlPlaneSpec{
+			ExtraProbes: map[string]plan.Probe{
+				"my-cni": {},
+			},
+		},
+	}
+
+	if err := validateProbes(controlPlane); err == nil {
+		t.Error("expected validateProbes to reject an extra probe with no action set")
+	}
+}
+
+func TestValidateProbeAction(t *testing.T) {
+	cases := []struct {
+		name    string
+		probe   plan.Probe
+		wantErr bool
+	}{
+		{"http only", plan.Probe{HTTPGetAction: plan.HTTPGetAction{URL: "http://127.0.0.1/healthz"}}, false},
+		{"tcp only", plan.Probe{TCPSocketAction: plan.TCPSocketAction{Port: "2379"}}, false},
+		{"exec only", plan.Probe{ExecAction: plan.ExecAction{Command: []string{"ctr", "version"}}}, false},
+		{"none", plan.Probe{}, true},
+		{"http and tcp", plan.Probe{
+			HTTPGetAction:   plan.HTTPGetAction{URL: "http://127.0.0.1/healthz"},
+			TCPSocketAction: plan.TCPSocketAction{Port: "2379"},
+		}, true},
+	}
+	for _, c := range cases {
+		err := validateProbeAction(c.name, c.probe)
+		if c.wantErr && err == nil {
+			t.Errorf("%s: expected an error, got none", c.name)
+		}
+		if !c.wantErr && err != nil {
+			t.Errorf("%s: expected no error, got %v", c.name, err)
+		}
+	}
+}
+
+func TestEtcdMetricsExplicitlyDisabled(t *testing.T) {
+	if etcdMetricsExplicitlyDisabled([]string{"--listen-metrics-urls=http://127.0.0.1:2381"}) {
+		t.Error("did not expect a configured --listen-metrics-urls to count as disabled")
+	}
+	if !etcdMetricsExplicitlyDisabled([]string{"--listen-metrics-urls="}) {
+		t.Error("expected an explicitly empty --listen-metrics-urls to count as disabled")
+	}
+	if etcdMetricsExplicitlyDisabled(nil) {
+		t.Error("did not expect no args at all to count as disabled")
+	}
+}
+
+func TestEtcdQuorumProbesAllFailing(t *testing.T) {
+	if etcdQuorumProbesAllFailing(nil) {
+		t.Error("did not expect no statuses at all to count as a quorum flap")
+	}
+	if etcdQuorumProbesAllFailing(map[string]plan.ProbeStatus{
+		"etcd-0": {Healthy: false},
+		"etcd-1": {Healthy: true},
+		"etcd-2": {Healthy: false},
+	}) {
+		t.Error("did not expect a single healthy member to count as a quorum flap")
+	}
+	if !etcdQuorumProbesAllFailing(map[string]plan.ProbeStatus{
+		"etcd-0": {Healthy: false},
+		"etcd-1": {Healthy: false},
+		"etcd-2": {Healthy: false},
+	}) {
+		t.Error("expected every member failing at once to count as a quorum flap")
+	}
+}
+
+func TestApplyEtcdQuorumFlapGuard(t *testing.T) {
+	nodePlan := plan.NodePlan{
+		Probes: map[string]plan.Probe{
+			"etcd": allProbes["etcd"],
+		},
+	}
+
+	recentReply := time.Now().Add(-time.Minute).Format(time.RFC3339)
+
+	flapping := applyEtcdQuorumFlapGuard(nodePlan, map[string]plan.ProbeStatus{
+		"etcd-0": {Healthy: false, LastReplyTime: recentReply},
+		"etcd-1": {Healthy: false, LastReplyTime: recentReply},
+	})
+	if flapping.Probes["etcd"].FailureThreshold != etcdQuorumFlapFailureThreshold {
+		t.Errorf("expected a whole-quorum flap to relax the etcd probe's failure threshold, got %d", flapping.Probes["etcd"].FailureThreshold)
+	}
+
+	// applyEtcdQuorumFlapGuard writes into nodePlan.Probes, so the partial case needs its own plan.
+	nodePlan = plan.NodePlan{
+		Probes: map[string]plan.Probe{
+			"etcd": allProbes["etcd"],
+		},
+	}
+	partial := applyEtcdQuorumFlapGuard(nodePlan, map[string]plan.ProbeStatus{
+		"etcd-0": {Healthy: false, LastReplyTime: recentReply},
+		"etcd-1": {Healthy: true, LastReplyTime: recentReply},
+	})
+	if partial.Probes["etcd"].FailureThreshold == etcdQuorumFlapFailureThreshold {
+		t.Error("did not expect a partial failure to relax the etcd probe's failure threshold")
+	}
+}
+
+func TestEtcdQuorumFlapWithinSuppressionWindow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if !etcdQuorumFlapWithinSuppressionWindow(map[string]plan.ProbeStatus{
+		"etcd-0": {LastReplyTime: now.Add(-time.Minute).Format(time.RFC3339)},
+		"etcd-1": {LastReplyTime: now.Add(-2 * time.Minute).Format(time.RFC3339)},
+	}, now) {
+		t.Error("expected two recent replies to stay within the suppression window")
+	}
+
+	if etcdQuorumFlapWithinSuppressionWindow(map[string]plan.ProbeStatus{
+		"etcd-0": {LastReplyTime: now.Add(-time.Minute).Format(time.RFC3339)},
+		"etcd-1": {LastReplyTime: now.Add(-10 * time.Minute).Format(time.RFC3339)},
+	}, now) {
+		t.Error("expected a member stale past etcdQuorumFlapMaxSuppressionSeconds to end the suppression window")
+	}
+
+	if etcdQuorumFlapWithinSuppressionWindow(map[string]plan.ProbeStatus{
+		"etcd-0": {LastReplyTime: now.Add(-time.Minute).Format(time.RFC3339)},
+		"etcd-1": {},
+	}, now) {
+		t.Error("expected a member that has never successfully replied to end the suppression window")
+	}
+}
+
+func TestApplyEtcdQuorumFlapGuardEscalatesPastSuppressionWindow(t *testing.T) {
+	nodePlan := plan.NodePlan{
+		Probes: map[string]plan.Probe{
+			"etcd": allProbes["etcd"],
+		},
+	}
+
+	stale := time.Now().Add(-10 * time.Minute).Format(time.RFC3339)
+	result := applyEtcdQuorumFlapGuard(nodePlan, map[string]plan.ProbeStatus{
+		"etcd-0": {Healthy: false, LastReplyTime: stale},
+		"etcd-1": {Healthy: false, LastReplyTime: stale},
+	})
+	if result.Probes["etcd"].FailureThreshold == etcdQuorumFlapFailureThreshold {
+		t.Error("expected a whole-quorum flap stale past the suppression window to escalate rather than relax")
+	}
+}
+
+func TestCollectEtcdProbeStatuses(t *testing.T) {
+	machines := []*capi.Machine{
+		{ObjectMeta: metav1.ObjectMeta{Name: "etcd-0"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "etcd-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "etcd-2"}},
+	}
+	reported := map[string]plan.ProbeStatus{
+		"etcd-0": {Healthy: true},
+		"etcd-1": {Healthy: false},
+	}
+
+	statuses := collectEtcdProbeStatuses(machines, func(m *capi.Machine) (plan.ProbeStatus, bool) {
+		status, ok := reported[m.Name]
+		return status, ok
+	})
+
+	if len(statuses) != 2 {
+		t.Fatalf("expected only the two machines with a reported status, got %d", len(statuses))
+	}
+	if !statuses["etcd-0"].Healthy {
+		t.Error("expected etcd-0's reported status to be carried through unchanged")
+	}
+	if statuses["etcd-1"].Healthy {
+		t.Error("expected etcd-1's reported status to be carried through unchanged")
+	}
+	if _, ok := statuses["etcd-2"]; ok {
+		t.Error("expected etcd-2, which has no reported status, to be left out of the snapshot")
+	}
+}
+
+func TestCloudControllerManagerEnabled(t *testing.T) {
+	enabled := &rkev1.RKEControlPlane{
+		Spec: rkev1.RKEControlPlaneSpec{
+			MachineGlobalConfig: rkev1.GenericMap{Data: map[string]interface{}{}},
+		},
+	}
+	if !cloudControllerManagerEnabled(enabled) {
+		t.Error("expected cloud-controller-manager to be enabled by default")
+	}
+
+	disabled := &rkev1.RKEControlPlane{
+		Spec: rkev1.RKEControlPlaneSpec{
+			MachineGlobalConfig: rkev1.GenericMap{Data: map[string]interface{}{"disable-cloud-controller": true}},
+		},
+	}
+	if cloudControllerManagerEnabled(disabled) {
+		t.Error("expected cloud-controller-manager to be disabled when disable-cloud-controller is set")
+	}
+}
+
+func TestReplaceCACertAndPortForProbesTCPSocket(t *testing.T) {
+	templated, err := replaceCACertAndPortForProbes(plan.Probe{
+		TCPSocketAction: plan.TCPSocketAction{Port: "%s"},
+	}, "", "6443")
+	if err != nil {
+		t.Fatalf("unexpected error rendering a templated TCP port: %v", err)
+	}
+	if templated.TCPSocketAction.Port != "6443" {
+		t.Errorf("expected the %%s template to be rendered to 6443, got %q", templated.TCPSocketAction.Port)
+	}
+
+	literal, err := replaceCACertAndPortForProbes(plan.Probe{
+		TCPSocketAction: plan.TCPSocketAction{Port: "2379"},
+	}, "", "6443")
+	if err != nil {
+		t.Fatalf("unexpected error on a literal TCP port: %v", err)
+	}
+	if literal.TCPSocketAction.Port != "2379" {
+		t.Errorf("expected a literal port with no %%s template to be left alone, got %q", literal.TCPSocketAction.Port)
+	}
+}
+
+func TestReplaceRuntimeForProbesDoesNotMutateSharedCommand(t *testing.T) {
+	shared := plan.Probe{
+		ExecAction: plan.ExecAction{Command: []string{"ctr", "--address", "/run/%s/containerd.sock", "version"}},
+	}
+	probes := map[string]plan.Probe{"containerd": shared}
+
+	rke2 := replaceRuntimeForProbes(probes, "rke2")
+	if got := rke2["containerd"].ExecAction.Command[2]; got != "/run/rke2/containerd.sock" {
+		t.Errorf("expected the rke2 runtime to be rendered into the command, got %q", got)
+	}
+
+	k3s := replaceRuntimeForProbes(probes, "k3s")
+	if got := k3s["containerd"].ExecAction.Command[2]; got != "/run/k3s/containerd.sock" {
+		t.Errorf("expected the k3s runtime to be rendered into the command, got %q", got)
+	}
+
+	if got := shared.ExecAction.Command[2]; got != "/run/%s/containerd.sock" {
+		t.Errorf("expected the original shared probe's command to be left untouched, got %q", got)
+	}
+}